@@ -1,18 +1,149 @@
 package httpserver
 
 import (
+	"fmt"
 	"net"
+	"net/http"
+	"sort"
 	"strings"
 )
 
 // vhostTrie facilitates virtual hosting. It matches
 // requests first by hostname (with support for
-// wildcards as TLS certificates support them), then
-// by longest matching path.
+// wildcards as TLS certificates support them, plus
+// the leftmost multi-label "**" and partial-label
+// "api-*" forms described on matchHost, and static
+// host aliases registered with InsertAlias), then by
+// path, which may contain named parameters (:name)
+// and a single trailing catch-all segment (*name).
 type vhostTrie struct {
-	edges map[string]*vhostTrie
-	site  *SiteConfig // also known as a virtual host
-	path  string      // the path portion of the key for this node
+	edges map[string]*vhostTrie // host -> subtree (root node only)
+
+	// partialHosts and multiHosts are auxiliary lists, populated
+	// only on the root node, of host patterns that can't be
+	// looked up directly in edges because they don't pin down a
+	// fixed, literal label at every position. partialHosts is
+	// tried only when the exact lookup in edges misses, and
+	// multiHosts only when the single-label wildcard lookups in
+	// edges miss as well, keeping the common case an O(1) map lookup.
+	partialHosts []partialLabelHost
+	multiHosts   []multiLabelHost
+
+	// aliases hold the static host-to-host map registered with
+	// InsertAlias, also populated only on the root node. aliasChain
+	// records every alias exactly as inserted (alias -> canonical)
+	// so InsertAlias can detect cycles; aliasExact/aliasPartial/
+	// aliasMulti mirror edges/partialHosts/multiHosts so matchAlias
+	// can resolve a host to its canonical host using the same
+	// precedence as matchHost.
+	aliasChain   map[string]string
+	aliasExact   map[string]string
+	aliasPartial []partialLabelAlias
+	aliasMulti   []multiLabelAlias
+
+	literal      map[string]*vhostTrie // exact path segment -> subtree
+	param        *vhostTrie            // dynamic segment, e.g. ":id"
+	paramName    string                // name of the param edge, without the leading colon
+	catchall     *vhostTrie            // trailing wildcard segment, e.g. "*rest"; always a leaf
+	catchallName string                // name of the catchall edge, without the leading asterisk
+
+	site    *SiteConfig            // method-agnostic virtual host, registered with Insert
+	methods map[string]*SiteConfig // per-HTTP-method virtual hosts, registered with InsertMethod
+	path    string                 // the path portion of the key for this node
+}
+
+// hasRoute reports whether a site is registered at t, whether
+// method-agnostically (Insert) or per-method (InsertMethod).
+func (t *vhostTrie) hasRoute() bool {
+	return t.site != nil || len(t.methods) > 0
+}
+
+// partialLabelHost matches a host whose labels are equal in
+// number to labels, where every label of labels is either a
+// literal to match exactly or a single label containing a "*"
+// to be matched with matchPartialLabel, e.g. "api-*" or "*-staging".
+type partialLabelHost struct {
+	labels []string
+	branch *vhostTrie
+}
+
+func (p partialLabelHost) matches(hostLabels []string) bool {
+	return partialLabelsMatch(p.labels, hostLabels)
+}
+
+// partialLabelsMatch reports whether hostLabels matches patternLabels
+// label-for-label, where a pattern label may be a literal to match
+// exactly or contain a "*" to be matched with matchPartialLabel.
+func partialLabelsMatch(patternLabels, hostLabels []string) bool {
+	if len(hostLabels) != len(patternLabels) {
+		return false
+	}
+	for i, label := range patternLabels {
+		if label == hostLabels[i] {
+			continue
+		}
+		if !strings.Contains(label, "*") || !matchPartialLabel(label, hostLabels[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPartialLabel reports whether label matches pattern, a
+// single host label containing exactly one "*" wildcard, e.g.
+// "api-*" matches "api-east" and "*-staging" matches "web-staging".
+func matchPartialLabel(pattern, label string) bool {
+	idx := strings.IndexByte(pattern, '*')
+	if idx < 0 {
+		return pattern == label
+	}
+	prefix, suffix := pattern[:idx], pattern[idx+1:]
+	return len(label) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(label, prefix) &&
+		strings.HasSuffix(label, suffix)
+}
+
+// multiLabelHost matches a host whose trailing labels equal
+// suffix, regardless of how many leading labels (including
+// zero) precede them, e.g. suffix ["example","com"] (from
+// "**.example.com") matches "example.com" and "a.b.example.com".
+type multiLabelHost struct {
+	suffix []string
+	branch *vhostTrie
+}
+
+func (m multiLabelHost) matches(hostLabels []string) bool {
+	return multiLabelSuffixMatches(m.suffix, hostLabels)
+}
+
+// multiLabelSuffixMatches reports whether hostLabels ends with
+// suffix, regardless of how many leading labels (including zero)
+// precede it.
+func multiLabelSuffixMatches(suffix, hostLabels []string) bool {
+	if len(hostLabels) < len(suffix) {
+		return false
+	}
+	tail := hostLabels[len(hostLabels)-len(suffix):]
+	for i, label := range suffix {
+		if label != tail[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// partialLabelAlias and multiLabelAlias are the alias-table
+// counterparts of partialLabelHost and multiLabelHost: instead of
+// pointing straight at a subtree, they resolve to a canonical host
+// string that must then be looked up with matchHost.
+type partialLabelAlias struct {
+	labels    []string
+	canonical string
+}
+
+type multiLabelAlias struct {
+	suffix    []string
+	canonical string
 }
 
 // newVHostTrie returns a new vhostTrie.
@@ -21,44 +152,329 @@ func newVHostTrie() *vhostTrie {
 }
 
 // Insert adds stack to t keyed by key. The key should be
-// a valid "host/path" combination (or just host).
-func (t *vhostTrie) Insert(key string, site *SiteConfig) {
+// a valid "host/path" combination (or just host). The path
+// may contain named parameter segments like ":id" and end
+// with a single catch-all segment like "*rest". Insert returns
+// an error, rather than taking down the process, if key's param
+// or catchall name conflicts with one already registered at the
+// same route edge — this runs during Caddyfile loading, including
+// on a live graceful reload, so a config typo must be rejected,
+// not crash the running server.
+func (t *vhostTrie) Insert(key string, site *SiteConfig) error {
 	host, path := t.splitHostPath(key)
-	if _, ok := t.edges[host]; !ok {
-		t.edges[host] = newVHostTrie()
+	return t.insertHost(host).insertPath(splitPathSegments(path), path, site)
+}
+
+// insertHost returns the (possibly newly created) subtree for
+// host, additionally registering host in the partialHosts or
+// multiHosts auxiliary lists if it uses one of those wildcard
+// forms (see matchHost). insertHost is called once per path
+// inserted under host, not once per distinct host, so the
+// auxiliary-list registration only happens the first time host's
+// branch is created; otherwise a host with many paths registered
+// under it (e.g. "api-*.example.com/a", ".../b", ".../:id") would
+// pile up one duplicate entry per path instead of staying the
+// small, O(1)-ish list matchHost expects.
+func (t *vhostTrie) insertHost(host string) *vhostTrie {
+	branch, exists := t.edges[host]
+	if exists {
+		return branch
 	}
-	t.edges[host].insertPath(path, path, site)
+	branch = newVHostTrie()
+	t.edges[host] = branch
+
+	labels := strings.Split(host, ".")
+
+	if labels[0] == "**" {
+		t.multiHosts = append(t.multiHosts, multiLabelHost{suffix: labels[1:], branch: branch})
+		return branch
+	}
+
+	for _, label := range labels {
+		if label != "*" && strings.Contains(label, "*") {
+			t.partialHosts = append(t.partialHosts, partialLabelHost{labels: labels, branch: branch})
+			break
+		}
+	}
+
+	return branch
+}
+
+// InsertAlias registers alias so that requests for it are served by
+// whatever site is (or later becomes) registered under canonical,
+// as resolved by matchHost. alias and canonical may use any of the
+// wildcard forms matchHost understands. InsertAlias rejects alias
+// if it would create a cycle with an existing alias; it is meant
+// to be called on the root trie only.
+func (t *vhostTrie) InsertAlias(alias, canonical string) error {
+	alias, canonical = strings.ToLower(alias), strings.ToLower(canonical)
+	if alias == canonical {
+		return fmt.Errorf("alias %q cannot resolve to itself", alias)
+	}
+	if t.aliasChain == nil {
+		t.aliasChain = make(map[string]string)
+		t.aliasExact = make(map[string]string)
+	}
+
+	// walk the existing chain starting at canonical; if it leads
+	// back to alias, inserting alias -> canonical would close a
+	// loop. this only catches cycles formed from exact alias keys,
+	// which covers the vanity-domain use case this directive is
+	// for; since Match only ever takes a single alias hop, a cycle
+	// among wildcard aliases can't loop at request time regardless.
+	for cursor, seen := canonical, 0; ; seen++ {
+		if cursor == alias {
+			return fmt.Errorf("alias %q would create a cycle via %q", alias, canonical)
+		}
+		next, ok := t.aliasChain[cursor]
+		if !ok || seen > len(t.aliasChain) {
+			break
+		}
+		cursor = next
+	}
+
+	t.aliasChain[alias] = canonical
+	t.aliasExact[alias] = canonical
+
+	labels := strings.Split(alias, ".")
+	switch {
+	case labels[0] == "**":
+		t.aliasMulti = append(t.aliasMulti, multiLabelAlias{suffix: labels[1:], canonical: canonical})
+	default:
+		for _, label := range labels {
+			if label != "*" && strings.Contains(label, "*") {
+				t.aliasPartial = append(t.aliasPartial, partialLabelAlias{labels: labels, canonical: canonical})
+				break
+			}
+		}
+	}
+
+	return nil
 }
 
-// insertPath expects t to be a host node (not a root node),
-// and inserts site into the t according to remainingPath.
-func (t *vhostTrie) insertPath(remainingPath, originalPath string, site *SiteConfig) {
-	if remainingPath == "" {
-		t.site = site
-		t.path = originalPath
-		return
+// matchAlias resolves host to its canonical host using the aliases
+// registered with InsertAlias, trying exact, partial-label,
+// single-label, then multi-label forms, the same precedence order
+// as matchHost.
+func (t *vhostTrie) matchAlias(host string) (string, bool) {
+	if canonical, ok := t.aliasExact[host]; ok {
+		return canonical, true
 	}
-	ch := string(remainingPath[0])
-	if _, ok := t.edges[ch]; !ok {
-		t.edges[ch] = newVHostTrie()
+
+	labels := strings.Split(host, ".")
+
+	for _, p := range t.aliasPartial {
+		if partialLabelsMatch(p.labels, labels) {
+			return p.canonical, true
+		}
+	}
+
+	wildLabels := append([]string(nil), labels...)
+	for i := range wildLabels {
+		wildLabels[i] = "*"
+		candidate := strings.Join(wildLabels, ".")
+		if canonical, ok := t.aliasExact[candidate]; ok {
+			return canonical, true
+		}
+	}
+
+	for _, m := range t.aliasMulti {
+		if multiLabelSuffixMatches(m.suffix, labels) {
+			return m.canonical, true
+		}
 	}
-	t.edges[ch].insertPath(remainingPath[1:], originalPath, site)
+
+	return "", false
+}
+
+// insertPath expects t to be a host node (not a root node), and
+// registers site as the method-agnostic virtual host for the
+// path described by segments.
+func (t *vhostTrie) insertPath(segments []string, originalPath string, site *SiteConfig) error {
+	leaf, err := t.pathNode(segments)
+	if err != nil {
+		return err
+	}
+	leaf.site = site
+	leaf.path = originalPath
+	return nil
 }
 
-// Match returns the virtual host (site) in v with
-// the closest match to key. If there was a match,
-// it returns the SiteConfig and the path portion of
-// the key used to make the match. The matched path
-// would be a prefix of the path portion of the
-// key, if not the whole path portion of the key.
-// If there is no match, nil and empty string will
-// be returned.
+// InsertMethod is like Insert, but restricts site to requests
+// using method (case-insensitive). key's path may share literal,
+// param, and catchall edges with method-agnostic and other
+// per-method registrations at the same path; matchPath's edge
+// priority (literal, then param, then catchall) is unaffected by
+// the method dimension.
+func (t *vhostTrie) InsertMethod(key, method string, site *SiteConfig) error {
+	host, path := t.splitHostPath(key)
+	leaf, err := t.insertHost(host).pathNode(splitPathSegments(path))
+	if err != nil {
+		return err
+	}
+	if leaf.methods == nil {
+		leaf.methods = make(map[string]*SiteConfig)
+	}
+	leaf.methods[strings.ToUpper(method)] = site
+	leaf.path = path
+	return nil
+}
+
+// pathNode returns the (possibly newly created) node reached by
+// following segments from t, creating literal, param, or catchall
+// edges as needed. Literal segments, the param edge, and the
+// catchall edge are kept distinct so that matchPath can try them
+// in a fixed, deterministic priority order regardless of
+// insertion order. A catchall is always a leaf; any segments
+// after it are ignored.
+//
+// The param and catchall edges are shared by every route that
+// passes through this node, so their name is fixed by whichever
+// route creates the edge first; pathNode returns an error if a
+// later route tries to reuse the edge under a different name;
+// e.g. registering both "/users/:id" and "/users/:userId" would
+// otherwise silently report the wrong parameter name to whichever
+// route loses the race. Insert/InsertMethod run from Caddyfile
+// loading, including on a live graceful reload, so a config typo
+// like this must be rejected rather than crash the process — the
+// same reasoning InsertAlias uses for rejecting alias cycles.
+func (t *vhostTrie) pathNode(segments []string) (*vhostTrie, error) {
+	if len(segments) == 0 {
+		return t, nil
+	}
+
+	seg := segments[0]
+	switch {
+	case strings.HasPrefix(seg, "*"):
+		name := strings.TrimPrefix(seg, "*")
+		if t.catchall == nil {
+			t.catchall = newVHostTrie()
+			t.catchallName = name
+		} else if t.catchallName != name {
+			return nil, fmt.Errorf("httpserver: catchall %q conflicts with existing catchall %q on the same route", seg, "*"+t.catchallName)
+		}
+		return t.catchall, nil
+
+	case strings.HasPrefix(seg, ":"):
+		name := strings.TrimPrefix(seg, ":")
+		if t.param == nil {
+			t.param = newVHostTrie()
+			t.paramName = name
+		} else if t.paramName != name {
+			return nil, fmt.Errorf("httpserver: param %q conflicts with existing param %q on the same route", seg, ":"+t.paramName)
+		}
+		return t.param.pathNode(segments[1:])
+
+	default:
+		if t.literal == nil {
+			t.literal = make(map[string]*vhostTrie)
+		}
+		if _, ok := t.literal[seg]; !ok {
+			t.literal[seg] = newVHostTrie()
+		}
+		return t.literal[seg].pathNode(segments[1:])
+	}
+}
+
+// resolveMethod returns the site registered at t for method, along
+// with whether method is allowed there and, if t has any
+// per-method registrations, the sorted list of methods allowed at
+// t. If t has no per-method registrations at all, today's
+// method-agnostic behavior is preserved: any method is allowed
+// and allowed is nil.
+func (t *vhostTrie) resolveMethod(method string) (site *SiteConfig, methodAllowed bool, allowed []string) {
+	if len(t.methods) == 0 {
+		return t.site, true, nil
+	}
+	allowed = sortedMethodKeys(t.methods)
+	if site, ok := t.methods[method]; ok {
+		return site, true, allowed
+	}
+	if t.site != nil {
+		// a method-agnostic registration alongside explicit method
+		// buckets acts as a catch-all for any other method.
+		return t.site, true, allowed
+	}
+	return nil, false, allowed
+}
+
+// subtreeAllowedMethods returns the sorted, de-duplicated union of
+// every method registered with InsertMethod at or beneath t, for
+// answering "OPTIONS *" and "OPTIONS /path" automatically. It
+// returns nil if the subtree has no per-method registrations.
+func (t *vhostTrie) subtreeAllowedMethods() []string {
+	set := make(map[string]bool)
+	t.collectAllowedMethods(set)
+	if len(set) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(set))
+	for method := range set {
+		list = append(list, method)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (t *vhostTrie) collectAllowedMethods(set map[string]bool) {
+	for method := range t.methods {
+		set[method] = true
+	}
+	for _, child := range t.literal {
+		child.collectAllowedMethods(set)
+	}
+	if t.param != nil {
+		t.param.collectAllowedMethods(set)
+	}
+	if t.catchall != nil {
+		for method := range t.catchall.methods {
+			set[method] = true
+		}
+	}
+}
+
+func sortedMethodKeys(methods map[string]*SiteConfig) []string {
+	list := make([]string, 0, len(methods))
+	for method := range methods {
+		list = append(list, method)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Match returns the virtual host (site) in t with the closest
+// match to key, restricted to method (case-insensitive; treated
+// as GET if empty). If there was a host and path match, it
+// returns the path portion of the key used to make the match and
+// any named path parameters resolved along the way (nil if
+// none), regardless of methodAllowed. The matched path would be
+// a prefix of the path portion of the key, if not the whole path
+// portion of the key. If there is no host/path match at all, the
+// zero value of every return is given.
+//
+// If the path matched but method did not, site is nil,
+// methodAllowed is false, and allowedMethods lists every method
+// registered at that path, for the caller to answer with 405 and
+// an Allow header. If method is OPTIONS, site is nil, methodAllowed
+// is true, and allowedMethods is the union of every method
+// registered at or beneath the matched path, for the caller to
+// answer the preflight directly instead of invoking a site. For
+// OPTIONS a host match is enough: if no route matches the path,
+// the union is taken from the node the path walks to (see
+// structuralNode) and path is that node's path, "" if it has none.
 //
 // A typical key will be in the form "host" or "host/path".
-func (t *vhostTrie) Match(key string) (*SiteConfig, string) {
-	host, path := t.splitHostPath(key)
-	// try the given host, then, if no match, try wildcard hosts
+func (t *vhostTrie) Match(key, method string) (site *SiteConfig, path string, params map[string]string, methodAllowed bool, allowedMethods []string) {
+	host, reqPath := t.splitHostPath(key)
+	// try the given host, then, if no match, resolve it through a
+	// registered alias (a single extra lookup), then finally fall
+	// back to the wildcard hosts.
 	branch := t.matchHost(host)
+	if branch == nil {
+		if canonical, ok := t.matchAlias(host); ok {
+			branch = t.matchHost(canonical)
+		}
+	}
 	if branch == nil {
 		branch = t.matchHost("0.0.0.0")
 	}
@@ -66,56 +482,151 @@ func (t *vhostTrie) Match(key string) (*SiteConfig, string) {
 		branch = t.matchHost("")
 	}
 	if branch == nil {
-		return nil, ""
+		return nil, "", nil, false, nil
 	}
-	node := branch.matchPath(path)
+
+	segments := splitPathSegments(reqPath)
+
+	if strings.EqualFold(method, http.MethodOptions) {
+		// OPTIONS unions allowed methods across the whole matched
+		// subtree, so it must not bail out just because the node
+		// at this exact path has no route of its own (e.g. "OPTIONS
+		// *" against a host whose only registrations are deeper,
+		// like "/api"); fall back to the node matchPath structurally
+		// walks to, even if unrouted, and union from there.
+		pathParams := make(map[string]string)
+		node := branch.matchPath(segments, pathParams)
+		if node == nil {
+			node = branch.structuralNode(segments)
+			pathParams = nil
+		} else if len(pathParams) == 0 {
+			pathParams = nil
+		}
+		return nil, node.path, pathParams, true, node.subtreeAllowedMethods()
+	}
+
+	pathParams := make(map[string]string)
+	node := branch.matchPath(segments, pathParams)
 	if node == nil {
-		return nil, ""
+		return nil, "", nil, false, nil
 	}
-	return node.site, node.path
+	if len(pathParams) == 0 {
+		pathParams = nil
+	}
+
+	if method == "" {
+		method = http.MethodGet
+	}
+	site, methodAllowed, allowedMethods = node.resolveMethod(strings.ToUpper(method))
+	return site, node.path, pathParams, methodAllowed, allowedMethods
 }
 
-// matchHost returns the vhostTrie matching host. The matching
-// algorithm is the same as used to match certificates to host
-// with SNI during TLS handshakes. In other words, it supports,
-// to some degree, the use of wildcard (*) characters.
+// structuralNode walks t following remaining segments the same
+// way matchPath does (literal, then param, then catchall edges),
+// but — unlike matchPath — returns wherever that walk ends up
+// even if no node along the way has a route of its own. It's used
+// to locate the subtree to union allowed methods from when
+// answering OPTIONS for a path that has no direct registration.
+func (t *vhostTrie) structuralNode(remaining []string) *vhostTrie {
+	if len(remaining) == 0 {
+		return t
+	}
+	seg, rest := remaining[0], remaining[1:]
+	if child, ok := t.literal[seg]; ok {
+		return child.structuralNode(rest)
+	}
+	if t.param != nil {
+		return t.param.structuralNode(rest)
+	}
+	if t.catchall != nil {
+		return t.catchall
+	}
+	return t
+}
+
+// matchHost returns the vhostTrie matching host. Precedence,
+// most to least specific, is: exact match; partial-label
+// wildcards like "api-*.example.com"; single whole-label
+// wildcards like "*.example.com" (the same algorithm used to
+// match certificates to host with SNI during TLS handshakes);
+// then the leftmost multi-label wildcard "**.example.com",
+// which matches any number (including zero) of leading labels.
+// Callers additionally fall back to "0.0.0.0" and "" hosts.
 func (t *vhostTrie) matchHost(host string) *vhostTrie {
 	// try exact match
 	if subtree, ok := t.edges[host]; ok {
 		return subtree
 	}
 
-	// then try replacing labels in the host
-	// with wildcards until we get a match
 	labels := strings.Split(host, ".")
-	for i := range labels {
-		labels[i] = "*"
-		candidate := strings.Join(labels, ".")
+
+	for _, p := range t.partialHosts {
+		if p.matches(labels) {
+			return p.branch
+		}
+	}
+
+	// then try replacing labels in the host, one at a time from
+	// the left, with a literal "*" until we get a match; this
+	// only matches registrations with the same number of labels
+	// as host.
+	wildLabels := append([]string(nil), labels...)
+	for i := range wildLabels {
+		wildLabels[i] = "*"
+		candidate := strings.Join(wildLabels, ".")
 		if subtree, ok := t.edges[candidate]; ok {
 			return subtree
 		}
 	}
 
+	for _, m := range t.multiHosts {
+		if m.matches(labels) {
+			return m.branch
+		}
+	}
+
 	return nil
 }
 
-// matchPath traverses t until it finds the longest key matching
-// remainingPath, and returns its node.
-func (t *vhostTrie) matchPath(remainingPath string) *vhostTrie {
-	var longestMatch *vhostTrie
-	for len(remainingPath) > 0 {
-		ch := string(remainingPath[0])
-		next, ok := t.edges[ch]
-		if !ok {
-			break
+// matchPath traverses t, following remaining segments and
+// recording resolved param/catchall values into params, and
+// returns the node of the best matching registered path.
+func (t *vhostTrie) matchPath(remaining []string, params map[string]string) *vhostTrie {
+	if len(remaining) == 0 {
+		if t.hasRoute() {
+			return t
+		}
+		return nil
+	}
+
+	seg, rest := remaining[0], remaining[1:]
+
+	// literal segments always win over a param edge, and a
+	// param edge always wins over a trailing catchall edge.
+	if child, ok := t.literal[seg]; ok {
+		if node := child.matchPath(rest, params); node != nil {
+			return node
 		}
-		if next.site != nil {
-			longestMatch = next
+	}
+	if t.param != nil {
+		params[t.paramName] = seg
+		if node := t.param.matchPath(rest, params); node != nil {
+			return node
 		}
-		t = next
-		remainingPath = remainingPath[1:]
+		delete(params, t.paramName)
 	}
-	return longestMatch
+	if t.catchall != nil {
+		params[t.catchallName] = strings.Join(remaining, "/")
+		return t.catchall
+	}
+
+	// nothing deeper matched; fall back to this node if it's
+	// itself a registered route, preserving the trie's original
+	// longest-prefix-match behavior for unregistered sub-paths.
+	if t.hasRoute() {
+		return t
+	}
+	return nil
 }
 
 // splitHostPath separates host from path in key.
@@ -137,3 +648,32 @@ func (t *vhostTrie) splitHostPath(key string) (host, path string) {
 	}
 	return
 }
+
+// splitPathSegments splits path into its slash-separated
+// segments, ignoring the leading slash. "/" yields nil.
+func splitPathSegments(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// pathParamsCtxKey is the context key under which resolved
+// path parameters are stored on a request's context.
+type pathParamsCtxKey struct{}
+
+// PathParams returns the named path parameters resolved by
+// vhostTrie.Match for the current request, keyed by parameter name
+// (without the leading ":" or "*"). It returns nil if none were
+// resolved.
+//
+// PathParams only reads the request's context; nothing in this file
+// writes to it. Whatever calls vhostTrie.Match against a live
+// *http.Request during request handling is responsible for storing
+// Match's params return value under the same context key before
+// invoking downstream middleware, so that e.g. {path.id} works.
+func PathParams(r *http.Request) map[string]string {
+	params, _ := r.Context().Value(pathParamsCtxKey{}).(map[string]string)
+	return params
+}
@@ -0,0 +1,165 @@
+package httpserver
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestVHostTriePathParams(t *testing.T) {
+	trie := newVHostTrie()
+	users := &SiteConfig{}
+	userPosts := &SiteConfig{}
+	files := &SiteConfig{}
+
+	if err := trie.Insert("example.com/users/:id", users); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := trie.Insert("example.com/users/:id/posts/:pid", userPosts); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := trie.Insert("example.com/files/*rest", files); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	tests := []struct {
+		key        string
+		wantSite   *SiteConfig
+		wantParams map[string]string
+	}{
+		{"example.com/users/42", users, map[string]string{"id": "42"}},
+		{"example.com/users/42/posts/7", userPosts, map[string]string{"id": "42", "pid": "7"}},
+		{"example.com/files/a/b/c.txt", files, map[string]string{"rest": "a/b/c.txt"}},
+	}
+
+	for _, tc := range tests {
+		site, _, params, allowed, _ := trie.Match(tc.key, "GET")
+		if !allowed || site != tc.wantSite {
+			t.Errorf("Match(%q): got site %p allowed=%v, want %p", tc.key, site, allowed, tc.wantSite)
+		}
+		if !reflect.DeepEqual(params, tc.wantParams) {
+			t.Errorf("Match(%q): got params %v, want %v", tc.key, params, tc.wantParams)
+		}
+	}
+}
+
+func TestVHostTrieConflictingParamNameErrors(t *testing.T) {
+	trie := newVHostTrie()
+	if err := trie.Insert("example.com/users/:id", &SiteConfig{}); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := trie.Insert("example.com/users/:userId/extra", &SiteConfig{}); err == nil {
+		t.Error("expected an error inserting a conflicting param name on the same edge, got nil")
+	}
+}
+
+func TestVHostTrieConflictingCatchallNameErrors(t *testing.T) {
+	trie := newVHostTrie()
+	if err := trie.Insert("example.com/files/*rest", &SiteConfig{}); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := trie.Insert("example.com/files/*path", &SiteConfig{}); err == nil {
+		t.Error("expected an error inserting a conflicting catchall name on the same edge, got nil")
+	}
+}
+
+func TestVHostTrieHostWildcardPrecedence(t *testing.T) {
+	trie := newVHostTrie()
+	exact := &SiteConfig{}
+	partial := &SiteConfig{}
+	single := &SiteConfig{}
+	multi := &SiteConfig{}
+
+	if err := trie.Insert("api-east.example.com", exact); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := trie.Insert("api-*.example.com", partial); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := trie.Insert("*.example.com", single); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	// a different domain than example.com, so the single-label
+	// "*.example.com" registration above can never also match
+	// these hosts and the test isolates ** precedence cleanly.
+	if err := trie.Insert("**.beta.net", multi); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	tests := []struct {
+		host string
+		want *SiteConfig
+	}{
+		{"api-east.example.com", exact},   // exact beats everything
+		{"api-west.example.com", partial}, // partial-label beats single-label *
+		{"a.example.com", single},         // single-label * matches one leading label
+		{"beta.net", multi},               // ** matches zero leading labels
+		{"a.b.beta.net", multi},           // ** matches any number of leading labels
+	}
+
+	for _, tc := range tests {
+		site, _, _, allowed, _ := trie.Match(tc.host, "GET")
+		if !allowed || site != tc.want {
+			t.Errorf("Match(%q): got site %p allowed=%v, want %p", tc.host, site, allowed, tc.want)
+		}
+	}
+}
+
+func TestVHostTrieAlias(t *testing.T) {
+	trie := newVHostTrie()
+	canonical := &SiteConfig{}
+	if err := trie.Insert("example.com", canonical); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	if err := trie.InsertAlias("vanity.com", "example.com"); err != nil {
+		t.Fatalf("InsertAlias: %v", err)
+	}
+
+	site, _, _, allowed, _ := trie.Match("vanity.com", "GET")
+	if !allowed || site != canonical {
+		t.Errorf("Match(vanity.com): got site %p allowed=%v, want %p", site, allowed, canonical)
+	}
+
+	if err := trie.InsertAlias("example.com", "vanity.com"); err == nil {
+		t.Error("expected InsertAlias to reject a cycle, got nil error")
+	}
+}
+
+func TestVHostTrieMethodRouting(t *testing.T) {
+	trie := newVHostTrie()
+	getSite := &SiteConfig{}
+	postSite := &SiteConfig{}
+	if err := trie.InsertMethod("example.com/api", http.MethodGet, getSite); err != nil {
+		t.Fatalf("InsertMethod: %v", err)
+	}
+	if err := trie.InsertMethod("example.com/api", http.MethodPost, postSite); err != nil {
+		t.Fatalf("InsertMethod: %v", err)
+	}
+
+	site, _, _, allowed, allowedMethods := trie.Match("example.com/api", http.MethodGet)
+	if !allowed || site != getSite {
+		t.Fatalf("GET /api: got site %p allowed=%v", site, allowed)
+	}
+
+	site, _, _, allowed, allowedMethods = trie.Match("example.com/api", http.MethodDelete)
+	if allowed || site != nil {
+		t.Fatalf("DELETE /api: expected method not allowed, got site %p allowed=%v", site, allowed)
+	}
+	if !reflect.DeepEqual(allowedMethods, []string{"GET", "POST"}) {
+		t.Errorf("DELETE /api: got allowedMethods %v, want [GET POST]", allowedMethods)
+	}
+
+	// OPTIONS against the exact registered path.
+	_, _, _, allowed, allowedMethods = trie.Match("example.com/api", http.MethodOptions)
+	if !allowed || !reflect.DeepEqual(allowedMethods, []string{"GET", "POST"}) {
+		t.Errorf("OPTIONS /api: got allowed=%v allowedMethods %v, want true [GET POST]", allowed, allowedMethods)
+	}
+
+	// OPTIONS "*": no route registered at the host root itself,
+	// only deeper at /api; the union must still be found.
+	_, _, _, allowed, allowedMethods = trie.Match("example.com", http.MethodOptions)
+	if !allowed || !reflect.DeepEqual(allowedMethods, []string{"GET", "POST"}) {
+		t.Errorf(`OPTIONS "*": got allowed=%v allowedMethods %v, want true [GET POST]`, allowed, allowedMethods)
+	}
+}